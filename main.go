@@ -0,0 +1,87 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Command panicparse reads a goroutine dump from stdin and reformats it.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/panicparse/stack"
+	"github.com/mattn/panicparse/stack/report"
+)
+
+func mainImpl() error {
+	format := flag.String("format", "human", "output format: human, json, sarif or html")
+	snippetContext := flag.Int("snippet", 0, "lines of source context to attach to each call, 0 to disable")
+	snippetColor := flag.Bool("snippet-color", false, "ANSI-highlight attached source snippets")
+	flag.Parse()
+
+	in := &bytes.Buffer{}
+	if _, err := io.Copy(in, os.Stdin); err != nil {
+		return err
+	}
+	signal, rest := stack.ExtractSignalPreamble(in.Bytes())
+	if signal != nil {
+		fmt.Fprintf(os.Stderr, "panicparse: %s, pc=0x%x\n", signal.Signal, signal.PC)
+	}
+	extra := &bytes.Buffer{}
+	goroutines, err := stack.ParseDump(bytes.NewReader(rest), extra)
+	if err != nil {
+		return err
+	}
+	if signal != nil && len(goroutines) > 0 {
+		// The preamble precedes the whole dump, not any single goroutine, but
+		// the crashing goroutine is the one GOTRACEBACK=crash printed first.
+		goroutines[0].Signals = append(goroutines[0].Signals, *signal)
+	}
+
+	cache := &stack.Cache{
+		Snippet: stack.SnippetOptions{Context: *snippetContext, Highlight: *snippetColor},
+	}
+	cache.Augment(goroutines)
+
+	switch *format {
+	case "json":
+		return report.WriteJSON(os.Stdout, goroutines)
+	case "sarif":
+		return report.WriteSARIF(os.Stdout, goroutines)
+	case "html":
+		return report.WriteHTML(os.Stdout, goroutines)
+	case "human":
+		return writeHuman(os.Stdout, extra, goroutines)
+	default:
+		return fmt.Errorf("unknown -format %q", *format)
+	}
+}
+
+// writeHuman prints goroutines in panicparse's traditional plain-text form.
+func writeHuman(w io.Writer, extra *bytes.Buffer, goroutines []stack.Goroutine) error {
+	if _, err := w.Write(extra.Bytes()); err != nil {
+		return err
+	}
+	for _, g := range goroutines {
+		if _, err := fmt.Fprintf(w, "goroutine %d [%s]:\n", g.ID, g.State); err != nil {
+			return err
+		}
+		for _, c := range g.Signature.Stack {
+			if _, err := fmt.Fprintf(w, "%s(%s)\n\t%s:%d\n", c.Func.Raw, strings.Join(c.Args.Processed, ", "), c.SourcePath, c.Line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func main() {
+	if err := mainImpl(); err != nil {
+		fmt.Fprintf(os.Stderr, "panicparse: %s\n", err)
+		os.Exit(1)
+	}
+}
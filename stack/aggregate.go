@@ -0,0 +1,145 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+)
+
+// attachSignal records a dump's GOTRACEBACK=crash preamble, if any, against
+// the goroutine that was dumped first: the preamble precedes the whole
+// dump rather than any single goroutine, but the first one printed is the
+// one that crashed.
+func attachSignal(goroutines []Goroutine, signal *SignalFrame) {
+	if signal != nil && len(goroutines) > 0 {
+		goroutines[0].Signals = append(goroutines[0].Signals, *signal)
+	}
+}
+
+// Entry summarizes every dump observed with a given fingerprint, letting
+// panicparse be used as a fleet-wide crash triage tool, analogous to how
+// pprof aggregates profiles rather than inspecting one at a time.
+type Entry struct {
+	Fingerprint [32]byte
+	Count       int
+	FirstSeen   string
+	LastSeen    string
+	Exemplar    []Goroutine
+}
+
+// Aggregator buckets goroutines from multiple dumps by their Signature
+// fingerprint.
+type Aggregator struct {
+	entries map[[32]byte]*Entry
+}
+
+// NewAggregator returns an empty Aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{entries: map[[32]byte]*Entry{}}
+}
+
+// Add buckets a single dump's goroutines, identified by source (a file
+// name, or a sequence number when reading a delimited stdin stream).
+func (a *Aggregator) Add(source string, goroutines []Goroutine) {
+	for _, g := range goroutines {
+		fp := g.Signature.Fingerprint()
+		e, ok := a.entries[fp]
+		if !ok {
+			e = &Entry{Fingerprint: fp, FirstSeen: source, Exemplar: []Goroutine{g}}
+			a.entries[fp] = e
+		}
+		e.Count++
+		e.LastSeen = source
+	}
+}
+
+// Entries returns every distinct fingerprint observed so far, in no
+// particular order.
+func (a *Aggregator) Entries() []*Entry {
+	out := make([]*Entry, 0, len(a.entries))
+	for _, e := range a.entries {
+		out = append(out, e)
+	}
+	return out
+}
+
+// AggregateDir reads one dump per file in dir and buckets them, using each
+// file's name as its source identifier.
+func AggregateDir(dir string) (*Aggregator, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	a := NewAggregator()
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		signal, rest := ExtractSignalPreamble(content)
+		goroutines, err := ParseDump(bytes.NewReader(rest), ioutil.Discard)
+		if err != nil {
+			continue
+		}
+		attachSignal(goroutines, signal)
+		a.Add(f.Name(), goroutines)
+	}
+	return a, nil
+}
+
+// aggregateDelim separates successive dumps on a single stdin stream.
+const aggregateDelim = "----\n"
+
+// AggregateStream reads dumps delimited by aggregateDelim from r and
+// buckets them, using their 1-based position in the stream as source.
+func AggregateStream(r io.Reader) (*Aggregator, error) {
+	a := NewAggregator()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	var buf bytes.Buffer
+	n := 0
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		n++
+		signal, rest := ExtractSignalPreamble(buf.Bytes())
+		goroutines, err := ParseDump(bytes.NewReader(rest), ioutil.Discard)
+		buf.Reset()
+		if err != nil {
+			return nil
+		}
+		attachSignal(goroutines, signal)
+		a.Add(strconv.Itoa(n), goroutines)
+		return nil
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line+"\n" == aggregateDelim {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kind categorizes the language a stack frame originates from, so the
+// source-augmentation pass in Cache.AugmentCall can tell a Go frame it
+// should parse apart from a cgo, assembly or bare C frame it can only
+// skip. It replaces the previous strings.HasSuffix(".go") check, which
+// could not tell "no source available" apart from "not a Go frame".
+type Kind int
+
+// Frame kinds, in the order a cgo call passes through them on its way from
+// Go down to the crash site: Go code, the cgo trampoline, raw assembly (no
+// file:line), then a C function proper. A dump prints them innermost first,
+// so ParseDump meets them in the reverse order.
+const (
+	KindGo Kind = iota
+	KindCgo
+	KindAsm
+	KindC
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindGo:
+		return "go"
+	case KindCgo:
+		return "cgo"
+	case KindAsm:
+		return "asm"
+	case KindC:
+		return "c"
+	default:
+		return "unknown"
+	}
+}
+
+// SignalFrame captures the register dump GOTRACEBACK=crash prints ahead of
+// the goroutine dump when a SIGSEGV/SIGABRT/SIGBUS/SIGILL/SIGFPE crash
+// involves cgo, e.g. a corrupt C buffer inside SQLite or an image codec.
+type SignalFrame struct {
+	Signal    string
+	PC        uint64
+	Registers map[string]uint64
+}
+
+var (
+	signalHeaderRe = regexp.MustCompile(`^(SIGSEGV|SIGABRT|SIGBUS|SIGILL|SIGFPE): .+$`)
+	registerRe     = regexp.MustCompile(`^([a-z][a-z0-9]*)\s+(0x[0-9a-f]+)(?:\s+([a-z][a-z0-9]*)\s+(0x[0-9a-f]+))?\s*$`)
+	cSharedObjRe   = regexp.MustCompile(`\.so(\.[0-9]+)*$`)
+)
+
+// classifyFrame determines the Kind of a stack frame from the source
+// location ParseDump extracted for it and the frame's own function name,
+// printed on the line above that location: a ".go" path is Go, a path ending in a shared object
+// (e.g. "libc.so.6") is a C frame, and no path at all (cgo trampolines and
+// raw assembly print a bare PC, no file:line) is either the cgo trampoline
+// itself, when funcName matches "runtime.cgocall" or "_cgo_", or otherwise
+// raw assembly.
+func classifyFrame(sourcePath, funcName string) Kind {
+	switch {
+	case strings.HasSuffix(sourcePath, ".go"):
+		return KindGo
+	case sourcePath == "":
+		if isCgoFrame(funcName) {
+			return KindCgo
+		}
+		return KindAsm
+	case cSharedObjRe.MatchString(sourcePath):
+		return KindC
+	default:
+		return KindC
+	}
+}
+
+// isCgoFrame reports whether funcName is the cgo call trampoline rather
+// than plain hand-written assembly: the runtime's own entry point into cgo
+// ("runtime.cgocall") or a generated "_cgo_"-prefixed symbol.
+func isCgoFrame(funcName string) bool {
+	return funcName == "runtime.cgocall" || strings.HasPrefix(funcName, "_cgo_")
+}
+
+// parseSignalPreamble recognizes the "SIGSEGV: segmentation violation ..."
+// header and subsequent register dump GOTRACEBACK=crash prints before the
+// first "goroutine N [...]:" block, returning the parsed SignalFrame and
+// the lines that remain to be parsed as goroutines. Leading blank lines,
+// as panicparse already tolerates ahead of "panic: ...", are skipped.
+func parseSignalPreamble(lines []string) (*SignalFrame, []string) {
+	start := 0
+	for start < len(lines) && lines[start] == "" {
+		start++
+	}
+	if start >= len(lines) || !signalHeaderRe.MatchString(lines[start]) {
+		return nil, lines
+	}
+	sf := &SignalFrame{
+		Signal:    strings.SplitN(lines[start], ":", 2)[0],
+		Registers: map[string]uint64{},
+	}
+	i := start + 1
+	for ; i < len(lines); i++ {
+		m := registerRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		addRegister(sf, m[1], m[2])
+		if m[3] != "" {
+			addRegister(sf, m[3], m[4])
+		}
+	}
+	return sf, lines[i:]
+}
+
+// ExtractSignalPreamble strips a GOTRACEBACK=crash signal/register preamble
+// from the front of a raw dump, if present, so the remainder can be handed
+// to ParseDump unmodified. It returns the parsed SignalFrame, or nil if the
+// dump carries no such preamble, and the remaining bytes.
+//
+// ParseDump itself has no notion of this preamble; callers that care about
+// GOTRACEBACK=crash dumps must call ExtractSignalPreamble first, as main.go
+// does.
+func ExtractSignalPreamble(dump []byte) (*SignalFrame, []byte) {
+	lines := strings.Split(string(dump), "\n")
+	sf, rest := parseSignalPreamble(lines)
+	if sf == nil {
+		return nil, dump
+	}
+	return sf, []byte(strings.Join(rest, "\n"))
+}
+
+func addRegister(sf *SignalFrame, name, hex string) {
+	if v, err := strconv.ParseUint(strings.TrimPrefix(hex, "0x"), 16, 64); err == nil {
+		sf.Registers[name] = v
+		if name == "pc" || name == "rip" || name == "eip" {
+			sf.PC = v
+		}
+	}
+}
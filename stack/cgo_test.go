@@ -0,0 +1,78 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/maruel/ut"
+)
+
+func TestClassifyFrame(t *testing.T) {
+	ut.AssertEqual(t, KindGo, classifyFrame("/root/main.go", "main.bar"))
+	ut.AssertEqual(t, KindC, classifyFrame("/lib/x86_64-linux-gnu/libc.so.6", "memcpy"))
+	ut.AssertEqual(t, KindAsm, classifyFrame("", "runtime.asmcgocall"))
+	ut.AssertEqual(t, KindCgo, classifyFrame("", "runtime.cgocall"))
+	ut.AssertEqual(t, KindCgo, classifyFrame("", "_cgo_topofstack"))
+}
+
+func TestParseSignalPreamble(t *testing.T) {
+	lines := []string{
+		"SIGSEGV: segmentation violation",
+		"rax    0x0",
+		"rbx    0x7f0000 rip    0x45b290",
+		"goroutine 1 [running]:",
+	}
+	sf, rest := parseSignalPreamble(lines)
+	ut.AssertEqual(t, "SIGSEGV", sf.Signal)
+	ut.AssertEqual(t, uint64(0x45b290), sf.Registers["rip"])
+	ut.AssertEqual(t, uint64(0x45b290), sf.PC)
+	ut.AssertEqual(t, []string{"goroutine 1 [running]:"}, rest)
+}
+
+func TestParseSignalPreambleAbsent(t *testing.T) {
+	lines := []string{"goroutine 1 [running]:"}
+	sf, rest := parseSignalPreamble(lines)
+	if sf != nil {
+		t.Fatal("expected no signal frame")
+	}
+	ut.AssertEqual(t, lines, rest)
+}
+
+// TestExtractSignalPreambleEndToEnd feeds a full GOTRACEBACK=crash-style
+// dump through ExtractSignalPreamble and then the real ParseDump, the way
+// main.go does, to confirm the preamble is stripped cleanly and the
+// remaining goroutine block still parses.
+func TestExtractSignalPreambleEndToEnd(t *testing.T) {
+	dump := "\nSIGSEGV: segmentation violation\n" +
+		"rax    0x0\n" +
+		"rbx    0x7f0000 rip    0x45b290\n" +
+		strings.TrimPrefix(mainCrash, "\n")
+
+	sf, rest := ExtractSignalPreamble([]byte(dump))
+	ut.AssertEqual(t, "SIGSEGV", sf.Signal)
+	ut.AssertEqual(t, uint64(0x45b290), sf.Registers["rip"])
+
+	extra := &bytes.Buffer{}
+	goroutines, err := ParseDump(bytes.NewReader(rest), extra)
+	ut.AssertEqual(t, nil, err)
+	ut.AssertEqual(t, "panic: ooh\n\n", extra.String())
+	ut.AssertEqual(t, 1, len(goroutines))
+}
+
+// TestAugmentCallSkipsCFrame confirms Cache.AugmentCall re-derives a frame's
+// Kind from its source location when call.Kind is left at its zero value,
+// as it is on a Call built by hand rather than by ParseDump. A C frame must
+// be skipped, with no read attempted against its (nonexistent, on this
+// machine) source path.
+func TestAugmentCallSkipsCFrame(t *testing.T) {
+	c := &Cache{}
+	call := &Call{SourcePath: "/lib/x86_64-linux-gnu/libc.so.6", Line: 1}
+	c.AugmentCall(call)
+	ut.AssertEqual(t, []string(nil), call.Args.Processed)
+	ut.AssertEqual(t, []SnippetLine(nil), call.Snippet)
+}
@@ -0,0 +1,43 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"crypto/sha256"
+	"regexp"
+	"strings"
+)
+
+// anonFuncSuffix matches the ".func1", ".func1.2" style suffixes the Go
+// compiler appends to anonymous functions, so they can be canonicalized
+// away: the same literal closure shifts suffix across builds even when the
+// panic is otherwise identical.
+var anonFuncSuffix = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
+
+// Fingerprint returns a stable hash of the bucket's signature, suitable for
+// deduplicating the same logical panic seen across many dumps. Only
+// function names are hashed: no addresses, no argument values. Runtime and
+// reflect frames are skipped since they vary with the Go version and
+// calling convention rather than with the bug itself.
+func (b *Bucket) Fingerprint() [32]byte {
+	return b.Signature.Fingerprint()
+}
+
+// Fingerprint hashes the normalized function names of s.Stack. See
+// Bucket.Fingerprint for the rationale.
+func (s *Signature) Fingerprint() [32]byte {
+	h := sha256.New()
+	for _, c := range s.Stack {
+		name := c.Func.Raw
+		if strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "reflect.") {
+			continue
+		}
+		h.Write([]byte(anonFuncSuffix.ReplaceAllString(name, ".funcN")))
+		h.Write([]byte{'\n'})
+	}
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
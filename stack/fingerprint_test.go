@@ -0,0 +1,38 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"testing"
+
+	"github.com/maruel/ut"
+)
+
+func TestFingerprintIgnoresRuntimeAndAddresses(t *testing.T) {
+	a := Signature{Stack: []Call{
+		{Func: Function{"runtime.gopanic"}},
+		{SourcePath: "/root/main.go", Line: 4, Func: Function{"main.bar"}, Args: Args{Values: []Arg{{Value: 1}}}},
+		{SourcePath: "/other/main.go", Line: 99, Func: Function{"main.bar"}, Args: Args{Values: []Arg{{Value: 2}}}},
+	}}
+	b := Signature{Stack: []Call{
+		{SourcePath: "/build/main.go", Line: 7, Func: Function{"main.bar"}, Args: Args{Values: []Arg{{Value: 3}}}},
+		{SourcePath: "/build/other.go", Line: 12, Func: Function{"main.bar"}, Args: Args{Values: []Arg{{Value: 4}}}},
+	}}
+	ut.AssertEqual(t, a.Fingerprint(), b.Fingerprint())
+}
+
+func TestFingerprintCanonicalizesAnonymousFuncs(t *testing.T) {
+	a := Signature{Stack: []Call{{Func: Function{"main.run.func1"}}}}
+	b := Signature{Stack: []Call{{Func: Function{"main.run.func2"}}}}
+	ut.AssertEqual(t, a.Fingerprint(), b.Fingerprint())
+}
+
+func TestFingerprintDiffersOnDifferentStacks(t *testing.T) {
+	a := Signature{Stack: []Call{{Func: Function{"main.bar"}}}}
+	b := Signature{Stack: []Call{{Func: Function{"main.baz"}}}}
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Fatal("expected different fingerprints")
+	}
+}
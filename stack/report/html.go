@@ -0,0 +1,91 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"html"
+	"io"
+
+	"github.com/mattn/panicparse/stack"
+)
+
+// WriteHTML renders goroutines as self-contained HTML, with each call
+// site's stack.Call.Snippet emitted as a <pre> block whose tokens are
+// wrapped in per-token <span> elements, so a traceback can be read without
+// an editor open alongside it.
+func WriteHTML(w io.Writer, goroutines []stack.Goroutine) error {
+	for _, g := range goroutines {
+		if _, err := fmt.Fprintf(w, "<h2>goroutine %d [%s]</h2>\n", g.ID, html.EscapeString(g.State)); err != nil {
+			return err
+		}
+		for _, c := range g.Signature.Stack {
+			if _, err := fmt.Fprintf(w, "<p>%s (%s:%d)</p>\n", html.EscapeString(c.Func.Raw), html.EscapeString(c.SourcePath), c.Line); err != nil {
+				return err
+			}
+			if len(c.Snippet) == 0 {
+				continue
+			}
+			if _, err := io.WriteString(w, "<pre>"); err != nil {
+				return err
+			}
+			for _, l := range c.Snippet {
+				if err := writeHTMLLine(w, l); err != nil {
+					return err
+				}
+			}
+			if _, err := io.WriteString(w, "</pre>\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeHTMLLine(w io.Writer, l stack.SnippetLine) error {
+	class := "line"
+	if l.Current {
+		class = "line line-current"
+	}
+	if _, err := fmt.Fprintf(w, "<span class=%q>%4d  %s</span>\n", class, l.Number, tokenizeHTML(l.Text)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// tokenizeHTML wraps each keyword/identifier/literal/comment token of a
+// line of Go source in a <span class="tok-..."> element, using the same
+// stack.Tokenize split that backs ANSI terminal highlighting, so the two
+// renderers can't drift on which tokens get colored.
+func tokenizeHTML(line string) string {
+	var out bytes.Buffer
+	for _, t := range stack.Tokenize(line) {
+		if class := htmlClassFor(t.Kind); class != "" {
+			out.WriteString(`<span class="` + class + `">`)
+			out.WriteString(html.EscapeString(t.Text))
+			out.WriteString("</span>")
+		} else {
+			out.WriteString(html.EscapeString(t.Text))
+		}
+	}
+	return out.String()
+}
+
+func htmlClassFor(tok token.Token) string {
+	switch {
+	case tok.IsKeyword():
+		return "tok-kw"
+	case tok == token.COMMENT:
+		return "tok-com"
+	case tok == token.IDENT:
+		return "tok-ident"
+	case tok.IsLiteral():
+		return "tok-lit"
+	default:
+		return ""
+	}
+}
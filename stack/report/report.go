@@ -0,0 +1,88 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package report serializes parsed panic dumps to machine-readable formats
+// so they can be piped into log aggregators, code-scanning dashboards and
+// other tooling that doesn't speak panicparse's human-oriented output.
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/mattn/panicparse/stack"
+)
+
+// jsonSchemaVersion is bumped whenever the shape of Dump changes in a
+// backward-incompatible way.
+const jsonSchemaVersion = 1
+
+// Dump is the root of the JSON representation of a set of goroutines.
+type Dump struct {
+	Version    int         `json:"version"`
+	Goroutines []Goroutine `json:"goroutines"`
+}
+
+// Goroutine is the JSON representation of a stack.Goroutine.
+type Goroutine struct {
+	ID     int     `json:"id"`
+	State  string  `json:"state"`
+	Wait   string  `json:"wait,omitempty"`
+	Locked bool    `json:"locked_to_thread,omitempty"`
+	Stack  []Frame `json:"stack"`
+}
+
+// Frame is the JSON representation of a single stack.Call.
+type Frame struct {
+	Func string   `json:"func"`
+	File string   `json:"file"`
+	Line int      `json:"line"`
+	Args []string `json:"args,omitempty"`
+}
+
+// ToDump converts parsed goroutines into their JSON-serializable form.
+func ToDump(goroutines []stack.Goroutine) Dump {
+	d := Dump{Version: jsonSchemaVersion, Goroutines: make([]Goroutine, len(goroutines))}
+	for i, g := range goroutines {
+		d.Goroutines[i] = Goroutine{
+			ID:     g.ID,
+			State:  g.State,
+			Wait:   waitFor(g),
+			Locked: g.Locked,
+			Stack:  framesFor(g.Signature.Stack),
+		}
+	}
+	return d
+}
+
+// waitFor renders how long a goroutine has been sitting in its current
+// state, as reported by the dump (e.g. "goroutine 1 [chan receive, 5
+// minutes]:"). Empty when the dump didn't carry a wait duration.
+func waitFor(g stack.Goroutine) string {
+	if g.Signature.SleepMax <= 0 {
+		return ""
+	}
+	return (time.Duration(g.Signature.SleepMax) * time.Minute).String()
+}
+
+func framesFor(calls []stack.Call) []Frame {
+	frames := make([]Frame, len(calls))
+	for i, c := range calls {
+		frames[i] = Frame{
+			Func: c.Func.Raw,
+			File: c.SourcePath,
+			Line: c.Line,
+			Args: c.Args.Processed,
+		}
+	}
+	return frames
+}
+
+// WriteJSON serializes goroutines as JSON following the Dump schema.
+func WriteJSON(w io.Writer, goroutines []stack.Goroutine) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(ToDump(goroutines))
+}
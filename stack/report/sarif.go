@@ -0,0 +1,129 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package report
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/mattn/panicparse/stack"
+)
+
+// sarifVersion is the SARIF spec version this package emits.
+const sarifVersion = "2.1.0"
+
+// sarifSchema is the published schema URL for sarifVersion.
+const sarifSchema = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	CodeFlows           []sarifCodeFlow   `json:"codeFlows"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifCodeFlow struct {
+	ThreadFlows []sarifThreadFlow `json:"threadFlows"`
+}
+
+type sarifThreadFlow struct {
+	Locations []sarifLocation `json:"locations"`
+}
+
+// WriteSARIF serializes goroutines as a SARIF 2.1.0 log, one result per
+// goroutine, so panicparse output can feed code-scanning dashboards that
+// already consume SARIF from other static-analysis tools.
+func WriteSARIF(w io.Writer, goroutines []stack.Goroutine) error {
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "panicparse"}}}
+	for _, g := range goroutines {
+		run.Results = append(run.Results, sarifResultFor(g))
+	}
+	log := sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(log)
+}
+
+func sarifResultFor(g stack.Goroutine) sarifResult {
+	calls := g.Signature.Stack
+	var locs []sarifLocation
+	for _, c := range calls {
+		locs = append(locs, sarifLocationFor(c))
+	}
+	result := sarifResult{
+		RuleID:  "panic",
+		Message: sarifMessage{Text: fmt.Sprintf("goroutine %d [%s]", g.ID, g.State)},
+		CodeFlows: []sarifCodeFlow{
+			{ThreadFlows: []sarifThreadFlow{{Locations: locs}}},
+		},
+		PartialFingerprints: map[string]string{
+			// Reuse stack.Signature.Fingerprint, the same hash the
+			// Aggregator buckets dumps by, rather than hashing calls with
+			// our own cruder, inconsistent logic: a SARIF result and an
+			// Aggregator entry for the same logical panic must agree.
+			"panicparse/v1": fingerprintToHex(g.Signature.Fingerprint()),
+		},
+	}
+	if len(locs) > 0 {
+		result.Locations = []sarifLocation{locs[0]}
+	}
+	return result
+}
+
+func sarifLocationFor(c stack.Call) sarifLocation {
+	return sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: c.SourcePath},
+			Region:           sarifRegion{StartLine: c.Line},
+		},
+	}
+}
+
+func fingerprintToHex(fp [32]byte) string {
+	return hex.EncodeToString(fp[:])
+}
@@ -0,0 +1,92 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"bytes"
+	"go/token"
+)
+
+// SnippetOptions controls how much source AugmentCall attaches to each Call
+// as context, and whether it is ANSI-highlighted for terminal output.
+type SnippetOptions struct {
+	// Context is the number of lines of source shown on each side of the
+	// call site. Zero (the default) disables snippet extraction entirely.
+	Context int
+	// Highlight tokenizes each line with go/scanner and wraps keywords,
+	// identifiers, literals and comments in ANSI color codes.
+	Highlight bool
+}
+
+// SnippetLine is one line of source surrounding a Call.
+type SnippetLine struct {
+	Number  int
+	Text    string
+	Current bool // true for the line the call site is on
+}
+
+const ansiReset = "\x1b[0m"
+
+// populateSnippet fills call.Snippet with the ±Context lines around
+// call.Line taken from the already-cached source, per c.Snippet.
+func (c *Cache) populateSnippet(call *Call) {
+	if c.Snippet.Context <= 0 {
+		return
+	}
+	src := c.files[call.SourcePath]
+	if len(src) == 0 {
+		return
+	}
+	lines := bytes.Split(src, []byte{'\n'})
+	lo := call.Line - c.Snippet.Context
+	if lo < 1 {
+		lo = 1
+	}
+	hi := call.Line + c.Snippet.Context
+	if hi > len(lines) {
+		hi = len(lines)
+	}
+	snippet := make([]SnippetLine, 0, hi-lo+1)
+	for l := lo; l <= hi; l++ {
+		text := string(lines[l-1])
+		if c.Snippet.Highlight {
+			text = highlightLine(text)
+		}
+		snippet = append(snippet, SnippetLine{Number: l, Text: text, Current: l == call.Line})
+	}
+	call.Snippet = snippet
+}
+
+// highlightLine tokenizes a single line of Go source with Tokenize and
+// wraps each keyword, identifier, literal and comment in ANSI color codes,
+// leaving everything else (operators, punctuation, whitespace) untouched.
+func highlightLine(line string) string {
+	var out bytes.Buffer
+	for _, t := range Tokenize(line) {
+		if color := ansiColorFor(t.Kind); color != "" {
+			out.WriteString(color)
+			out.WriteString(t.Text)
+			out.WriteString(ansiReset)
+		} else {
+			out.WriteString(t.Text)
+		}
+	}
+	return out.String()
+}
+
+func ansiColorFor(tok token.Token) string {
+	switch {
+	case tok.IsKeyword():
+		return "\x1b[35m" // magenta
+	case tok == token.COMMENT:
+		return "\x1b[90m" // gray
+	case tok == token.IDENT:
+		return "\x1b[36m" // cyan
+	case tok.IsLiteral():
+		return "\x1b[32m" // green
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maruel/ut"
+)
+
+func TestPopulateSnippet(t *testing.T) {
+	c := &Cache{
+		Snippet: SnippetOptions{Context: 1},
+		files:   map[string][]byte{"/root/main.go": []byte(mainSource)},
+	}
+	call := &Call{SourcePath: "/root/main.go", Line: 4}
+	c.populateSnippet(call)
+	expected := []SnippetLine{
+		{Number: 3, Text: `func bar(s string, i int) {`},
+		{Number: 4, Text: `	panic(s)`, Current: true},
+		{Number: 5, Text: `}`},
+	}
+	ut.AssertEqual(t, expected, call.Snippet)
+}
+
+func TestPopulateSnippetDisabledByDefault(t *testing.T) {
+	c := &Cache{files: map[string][]byte{"/root/main.go": []byte(mainSource)}}
+	call := &Call{SourcePath: "/root/main.go", Line: 4}
+	c.populateSnippet(call)
+	ut.AssertEqual(t, []SnippetLine(nil), call.Snippet)
+}
+
+func TestHighlightLineWrapsKeyword(t *testing.T) {
+	out := highlightLine("func bar(s string, i int) {")
+	expected := "\x1b[35mfunc\x1b[0m"
+	if !strings.Contains(out, expected) {
+		t.Fatalf("expected %q to contain %q", out, expected)
+	}
+}
+
+func TestHighlightLineStatementDoesNotPanic(t *testing.T) {
+	// Regression test: go/scanner emits an automatic-semicolon-insertion
+	// token at end-of-line (offset == len(line)), which used to be treated
+	// as consumed text and pushed `last` past len(line), panicking on the
+	// final line[last:] slice.
+	out := highlightLine(`	panic("boom")`)
+	if out == "" {
+		t.Fatal("expected non-empty highlighted output")
+	}
+}
+
+func TestHighlightLineColorsIdent(t *testing.T) {
+	out := highlightLine("bar(s)")
+	expected := "\x1b[36ms\x1b[0m"
+	if !strings.Contains(out, expected) {
+		t.Fatalf("expected %q to contain %q", out, expected)
+	}
+}
+
+func TestPopulateSnippetHighlightDoesNotPanic(t *testing.T) {
+	c := &Cache{
+		Snippet: SnippetOptions{Context: 1, Highlight: true},
+		files:   map[string][]byte{"/root/main.go": []byte(mainSource)},
+	}
+	call := &Call{SourcePath: "/root/main.go", Line: 4}
+	c.populateSnippet(call)
+	ut.AssertEqual(t, 3, len(call.Snippet))
+}
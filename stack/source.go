@@ -8,18 +8,27 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
-	"go/parser"
-	"go/token"
+	"go/types"
 	"io/ioutil"
 	"log"
-	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+
+	"golang.org/x/tools/go/packages"
 )
 
-// Cache is a cache of sources on the file system.
+// Cache is a cache of sources and of the type information derived from them
+// on the file system.
 type Cache struct {
-	files  map[string][]byte
-	parsed map[string]*parsedFile
+	// Snippet controls whether AugmentCall also attaches surrounding source
+	// lines to each Call. It is the caller's responsibility to set it before
+	// the first call to Augment or AugmentCall.
+	Snippet SnippetOptions
+
+	files map[string][]byte
+	pkgs  map[string]*packages.Package
 }
 
 // Augment processes source files to improve calls to be more descriptive.
@@ -37,8 +46,16 @@ func (c *Cache) Augment(goroutines []Goroutine) {
 //
 // It modifies call.
 func (c *Cache) AugmentCall(call *Call) {
-	if !strings.HasSuffix(call.SourcePath, ".go") {
-		// Ignore C and assembly.
+	kind := call.Kind
+	if kind == KindGo {
+		// ParseDump sets call.Kind from the frame's source location as it
+		// parses, but a Call built by hand (e.g. in a test) leaves it at its
+		// zero value (KindGo); re-derive it here so that case is still safe.
+		kind = classifyFrame(call.SourcePath, call.Func.Raw)
+	}
+	if kind != KindGo {
+		// Ignore cgo, assembly and C frames: there's no Go source, and for
+		// C frames not even necessarily a file on this machine.
 		return
 	}
 	if c.files == nil {
@@ -54,508 +71,265 @@ func (c *Cache) AugmentCall(call *Call) {
 	if len(c.files[call.SourcePath]) == 0 {
 		return
 	}
-	if err := c.getFuncArgs(call); err != nil {
-		c.files[call.SourcePath] = nil
-		log.Printf("Failed to parse %s: %s", call.SourcePath, err)
+	if err := c.resolveArgs(call); err != nil {
+		log.Printf("Failed to resolve types for %s: %s", call.SourcePath, err)
 	}
+	c.populateSnippet(call)
 }
 
 // Private stuff.
 
-func (c *Cache) getFuncArgs(call *Call) error {
-	if c.parsed == nil {
-		c.parsed = map[string]*parsedFile{}
+// resolveArgs finds the *types.Func enclosing call and renders call's raw
+// words according to the signature's parameter types, following Go's
+// calling convention: scalars, pointers, chans, maps and funcs take one
+// word, strings and interfaces take two (data/itab + len/data), slices
+// take three (ptr+len+cap), and arrays/structs recurse over their fields.
+func (c *Cache) resolveArgs(call *Call) error {
+	fn, err := c.funcAt(call.SourcePath, call.Line)
+	if err != nil {
+		return err
 	}
-
-	if _, ok := c.parsed[call.SourcePath]; !ok {
-		fset := token.NewFileSet()
-		src := c.files[call.SourcePath]
-		parsed, err := parser.ParseFile(fset, call.SourcePath, src, 0)
-		if err != nil {
-			c.parsed[call.SourcePath] = nil
-			return err
-		}
-		// Convert the line number into raw file offset.
-		offsets := []int{0, 0}
-		start := 0
-		for l := 1; start < len(src); l++ {
-			start += bytes.IndexByte(src[start:], '\n') + 1
-			offsets = append(offsets, start)
-		}
-		c.parsed[call.SourcePath] = &parsedFile{offsets, parsed}
-	} else if c.parsed[call.SourcePath] == nil {
+	if fn == nil {
+		// The function enclosing the call site wasn't found, e.g. the
+		// package failed to load or the frame is synthetic. Leave the raw
+		// values as-is.
 		return nil
 	}
-	return c.parsed[call.SourcePath].getFuncArgs(call)
-}
-
-type parsedFile struct {
-	lineToByteOffset []int
-	parsed           *ast.File
-}
-
-func (p *parsedFile) getFuncArgs(call *Call) error {
-	// We need to figure out what
-	done := false
-	//items := []*ast.FuncDecl{}
-	ast.Inspect(p.parsed, func(n ast.Node) bool {
-		if done {
-			return false
-		}
-		if n == nil {
-			return true
-		}
-		if int(n.Pos()) >= p.lineToByteOffset[call.Line] {
-			p.processNode(call, n)
-			done = true
-			return false
-		}
-		return true
-	})
-	return nil
-}
-
-func (p *parsedFile) processNode(call *Call, n ast.Node) {
-	switch n := n.(type) {
-	case *ast.ExprStmt:
-		switch n := n.X.(type) {
-		case *ast.CallExpr:
-			// TODO(maruel): It's the call site; we want the surrounding function.
-			p.processCallNode(call, n)
-		default:
-			panic(fmt.Errorf("%#v", n))
-		}
-
-	case *ast.FuncDecl:
-		// TODO(maruel): Ensure name is what is expected.
-		log.Printf("- Fn Decl: %#v", n.Name.Name)
-		for _, arg := range n.Type.Params.List {
-			switch arg := arg.Type.(type) {
-			case *ast.Ident:
-				log.Printf("  - Arg: %#v", arg.Name)
-			case *ast.SelectorExpr:
-				log.Printf("  - Arg: %#v", arg)
-			case *ast.StarExpr:
-				log.Printf("  - Arg: %#v", arg)
-			case *ast.ArrayType:
-				log.Printf("  - Arg: %#v", arg)
-			case *ast.InterfaceType:
-				log.Printf("  - Arg: %#v", arg)
-			case *ast.FuncType:
-				log.Printf("  - Arg: %#v", arg)
-			default:
-				panic(fmt.Errorf("Unexpected param type: %#v", arg))
-			}
-		}
-
-	default:
-		panic(fmt.Errorf("Unexpected statement: %#v", n))
-	}
-}
-
-func (p *parsedFile) processCallNode(call *Call, n *ast.CallExpr) {
-	// TODO(maruel): Ensure name is what is expected.
-	log.Printf("- Call: %#v (%d)", asIdent(n.Fun), len(call.Args.Values))
-	valIndex := 0
-	for i := 0; i < len(n.Args); i++ {
-		log.Printf("  i=%d len=%d, len=%d", i, len(n.Args))
-		switch arg := n.Args[i].(type) {
-		case *ast.Ident:
-			switch arg := arg.Obj.Decl.(type) {
-			case *ast.Field:
-				name := asIdent(arg.Type)
-				switch name {
-				case "error":
-					call.Args.Processed = append(call.Args.Processed, "error")
-				case "float32":
-					call.Args.Processed = append(call.Args.Processed, fmt.Sprintf("%g", math.Float32frombits(uint32(call.Args.Values[valIndex].Value))))
-				case "float64":
-					call.Args.Processed = append(call.Args.Processed, fmt.Sprintf("%g", math.Float64frombits(call.Args.Values[valIndex].Value)))
-				case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
-					call.Args.Processed = append(call.Args.Processed, fmt.Sprintf("%d", call.Args.Values[valIndex].Value))
-				case "string":
-					call.Args.Processed = append(call.Args.Processed, fmt.Sprintf("%s(0x%x, %d)", name, call.Args.Values[valIndex].Value, call.Args.Values[valIndex+1].Value))
-					valIndex++
-				default:
-					call.Args.Processed = append(call.Args.Processed, fmt.Sprintf("%s(0x%x)", name, call.Args.Values[valIndex].Value))
-				}
-				valIndex++
-				log.Printf("  - Arg1: %#v", name)
-
-			case *ast.ValueSpec:
-				name := asIdent(arg.Type)
-				log.Printf("  - Arg4: %#v", arg)
-				call.Args.Processed = append(call.Args.Processed, fmt.Sprintf("%s(0x%x)", name, call.Args.Values[valIndex].Value))
-				valIndex++
-			default:
-				panic(fmt.Errorf("Unexpected arg: %#v", arg))
-			}
-		case *ast.BasicLit:
-			log.Printf("  - Arg2: %s", arg.Value)
-		case *ast.BinaryExpr:
-			// Ignore.
-		case *ast.CallExpr:
-			log.Printf("  - Arg3: %v", arg)
-		default:
-			panic(fmt.Errorf("Unexpected arg: %#v", arg))
-		}
-	}
-}
-
-func asIdent(e ast.Expr) string {
-	if s, ok := e.(*ast.StarExpr); ok {
-		e = s.X
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok {
+		return nil
 	}
-	if s, ok := e.(*ast.SelectorExpr); ok {
-		e = s.X
+	words := call.Args.Values
+	var rendered []string
+	if sig.Recv() != nil {
+		s, n := c.renderArg(words, sig.Recv().Type(), call.SourcePath, call.Line)
+		rendered = append(rendered, s)
+		words = advance(words, n)
 	}
-	if ident, ok := e.(*ast.Ident); ok {
-		return ident.Name
+	for i := 0; i < sig.Params().Len() && len(words) > 0; i++ {
+		s, n := c.renderArg(words, sig.Params().At(i).Type(), call.SourcePath, call.Line)
+		rendered = append(rendered, s)
+		words = advance(words, n)
 	}
-	panic(fmt.Errorf("Unexpected expr: %#v", e))
+	call.Args.Processed = rendered
+	return nil
 }
 
-/*
-func getFuncArgsBroken(content []byte, line int) ([]string, error) {
-	log.Printf("getFuncArgsBroken(%d)", line)
-	var s scanner.Scanner
-	fset := token.NewFileSet()
-	file := fset.AddFile("", fset.Base(), len(content))
-	s.Init(file, content, nil, 0)
-	var args []string
-	// Convert the line number into raw file offset.
-	start := 0
-	for l := 1; l < line; l++ {
-		start += bytes.IndexByte(content[start:], '\n') + 1
+// funcAt loads the package containing path and returns the *types.Func
+// whose declaration encloses line, or nil if none is found.
+func (c *Cache) funcAt(path string, line int) (*types.Func, error) {
+	pkg, err := c.loadPackage(filepath.Dir(path))
+	if err != nil || pkg == nil {
+		return nil, err
 	}
-	log.Printf("start: %d", start)
-
-	for {
-		pos, tok, lit := s.Scan()
-		log.Printf("- %d %s %v", pos, tok, lit)
-		if int(pos) >= start {
-			log.Printf("- %d %s %v", pos, tok, lit)
-			for {
-				pos, tok, _ = s.Scan()
-				if tok == token.EOF {
-					break
-				}
-				if int(pos) != line {
-					break
-				}
+	for _, f := range pkg.Syntax {
+		tf := pkg.Fset.File(f.Pos())
+		if tf == nil || tf.Name() != path {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || line < tf.Line(fd.Pos()) || line > tf.Line(fd.End()) {
+				continue
+			}
+			if fn, ok := pkg.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+				return fn, nil
 			}
-		}
-		if tok == token.EOF {
-			break
 		}
 	}
-	return args, nil
+	return nil, nil
 }
-*/
 
-// Helper functions for common node lists. They may be empty.
-
-/*
-func walkIdentList(v Visitor, list []*Ident) {
-	for _, x := range list {
-		Walk(v, x)
+// loadPackage loads, and caches, the package found in dir with enough
+// information (types, syntax and dependencies) to resolve call arguments.
+func (c *Cache) loadPackage(dir string) (*packages.Package, error) {
+	if c.pkgs == nil {
+		c.pkgs = map[string]*packages.Package{}
 	}
-}
-
-func walkExprList(v Visitor, list []Expr) {
-	for _, x := range list {
-		Walk(v, x)
+	if pkg, ok := c.pkgs[dir]; ok {
+		return pkg, nil
 	}
-}
-
-func walkStmtList(v Visitor, list []Stmt) {
-	for _, x := range list {
-		Walk(v, x)
+	cfg := &packages.Config{
+		Mode: packages.NeedTypes | packages.NeedTypesInfo | packages.NeedSyntax | packages.NeedDeps,
+		Dir:  dir,
 	}
-}
-
-func walkDeclList(v Visitor, list []Decl) {
-	for _, x := range list {
-		Walk(v, x)
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		c.pkgs[dir] = nil
+		return nil, err
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Errors) != 0 {
+		// Best effort only; a package that doesn't load cleanly (e.g. the
+		// dump was taken on another machine) just means raw values remain.
+		c.pkgs[dir] = nil
+		return nil, nil
 	}
+	c.pkgs[dir] = pkgs[0]
+	return pkgs[0], nil
 }
 
-// Experimenting.
-func Walk(parent ast.Node, node ast.Node) {
-	// walk children
-	// (the order of the cases matches the order
-	// of the corresponding node types in ast.go)
-	switch n := node.(type) {
-
-	case *ast.Field:
-		if n.Doc != nil {
-			Walk(v, n.Doc)
-		}
-		walkIdentList(v, n.Names)
-		Walk(v, n.Type)
-		if n.Tag != nil {
-			Walk(v, n.Tag)
-		}
-		if n.Comment != nil {
-			Walk(v, n.Comment)
-		}
-
-	case *ast.FieldList:
-		for _, f := range n.List {
-			Walk(v, f)
-		}
-
-	// Expressions
-	case *ast.BadExpr, *ast.Ident, *ast.BasicLit:
-		// nothing to do
-
-	case *ast.Ellipsis:
-		if n.Elt != nil {
-			Walk(v, n.Elt)
-		}
-
-	case *ast.FuncLit:
-		Walk(v, n.Type)
-		Walk(v, n.Body)
+var errorIface = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
 
-	case *ast.CompositeLit:
-		if n.Type != nil {
-			Walk(v, n.Type)
-		}
-		walkExprList(v, n.Elts)
-
-	case *ast.ParenExpr:
-		Walk(v, n.X)
-
-	case *ast.SelectorExpr:
-		Walk(v, n.X)
-		Walk(v, n.Sel)
-
-	case *ast.IndexExpr:
-		Walk(v, n.X)
-		Walk(v, n.Index)
-
-	case *ast.SliceExpr:
-		Walk(v, n.X)
-		if n.Low != nil {
-			Walk(v, n.Low)
-		}
-		if n.High != nil {
-			Walk(v, n.High)
-		}
-		if n.Max != nil {
-			Walk(v, n.Max)
-		}
-
-	case *ast.TypeAssertExpr:
-		Walk(v, n.X)
-		if n.Type != nil {
-			Walk(v, n.Type)
-		}
-
-	case *ast.CallExpr:
-		Walk(v, n.Fun)
-		walkExprList(v, n.Args)
-
-	case *ast.StarExpr:
-		Walk(v, n.X)
-
-	case *ast.UnaryExpr:
-		Walk(v, n.X)
-
-	case *ast.BinaryExpr:
-		Walk(v, n.X)
-		Walk(v, n.Y)
-
-	case *ast.KeyValueExpr:
-		Walk(v, n.Key)
-		Walk(v, n.Value)
-
-	// Types
-	case *ast.ArrayType:
-		if n.Len != nil {
-			Walk(v, n.Len)
-		}
-		Walk(v, n.Elt)
-
-	case *ast.StructType:
-		Walk(v, n.Fields)
-
-	case *ast.FuncType:
-		if n.Params != nil {
-			Walk(v, n.Params)
-		}
-		if n.Results != nil {
-			Walk(v, n.Results)
-		}
-
-	case *ast.InterfaceType:
-		Walk(v, n.Methods)
-
-	case *ast.MapType:
-		Walk(v, n.Key)
-		Walk(v, n.Value)
-
-	case *ast.ChanType:
-		Walk(v, n.Value)
-
-	// Statements
-	case *ast.DeclStmt:
-		Walk(v, n.Decl)
-
-	case *ast.LabeledStmt:
-		Walk(v, n.Label)
-		Walk(v, n.Stmt)
-
-	case *ast.ExprStmt:
-		Walk(v, n.X)
-
-	case *ast.SendStmt:
-		Walk(v, n.Chan)
-		Walk(v, n.Value)
-
-	case *ast.IncDecStmt:
-		Walk(v, n.X)
-
-	case *ast.AssignStmt:
-		walkExprList(v, n.Lhs)
-		walkExprList(v, n.Rhs)
-
-	case *ast.GoStmt:
-		Walk(v, n.Call)
-
-	case *ast.DeferStmt:
-		Walk(v, n.Call)
-
-	case *ast.ReturnStmt:
-		walkExprList(v, n.Results)
-
-	case *ast.BranchStmt:
-		if n.Label != nil {
-			Walk(v, n.Label)
+// renderArg formats the value of type t found at the head of words and
+// returns the number of words it consumed.
+func (c *Cache) renderArg(words []Arg, t types.Type, path string, line int) (string, int) {
+	if len(words) == 0 {
+		return "", 0
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Info()&types.IsString != 0 {
+			if len(words) < 2 {
+				return fmt.Sprintf("%s(%#x)", t, words[0].Value), len(words)
+			}
+			if s, ok := c.dereferenceString(path, line, words[1].Value); ok {
+				return fmt.Sprintf("%q", s), 2
+			}
+			return fmt.Sprintf("string(0x%x, %d)", words[0].Value, words[1].Value), 2
 		}
+		return fmt.Sprintf("%d", words[0].Value), 1
 
-	case *ast.BlockStmt:
-		walkStmtList(v, n.List)
-
-	case *ast.IfStmt:
-		if n.Init != nil {
-			Walk(v, n.Init)
+	case *types.Pointer:
+		if words[0].Value == 0 {
+			return "nil", 1
 		}
-		Walk(v, n.Cond)
-		Walk(v, n.Body)
-		if n.Else != nil {
-			Walk(v, n.Else)
-		}
-
-	case *ast.CaseClause:
-		walkExprList(v, n.List)
-		walkStmtList(v, n.Body)
+		return fmt.Sprintf("(%s)(0x%x)", t, words[0].Value), 1
 
-	case *ast.SwitchStmt:
-		if n.Init != nil {
-			Walk(v, n.Init)
+	case *types.Interface:
+		if len(words) < 2 {
+			return fmt.Sprintf("(%s)(0x%x)", t, words[0].Value), len(words)
 		}
-		if n.Tag != nil {
-			Walk(v, n.Tag)
+		if words[0].Value == 0 && words[1].Value == 0 {
+			return "nil", 2
 		}
-		Walk(v, n.Body)
-
-	case *ast.TypeSwitchStmt:
-		if n.Init != nil {
-			Walk(v, n.Init)
-		}
-		Walk(v, n.Assign)
-		Walk(v, n.Body)
-
-	case *ast.CommClause:
-		if n.Comm != nil {
-			Walk(v, n.Comm)
+		if types.Implements(t, errorIface) {
+			// Mirror the *types.Basic string case above: the common case
+			// named in the request, error("msg"), is an error built
+			// straight from a message literal on the call's own line (e.g.
+			// errors.New("boom")). soleLiteralOnLine recovers it without
+			// needing to match a length against a word we don't have here
+			// (an error's second word is a data pointer, not a length).
+			if s, ok := c.soleLiteralOnLine(path, line); ok {
+				return fmt.Sprintf("error(%q)", s), 2
+			}
+			return fmt.Sprintf("error(0x%x, 0x%x)", words[0].Value, words[1].Value), 2
 		}
-		walkStmtList(v, n.Body)
+		return fmt.Sprintf("(%s)(0x%x, 0x%x)", t, words[0].Value, words[1].Value), 2
 
-	case *ast.SelectStmt:
-		Walk(v, n.Body)
+	case *types.Chan, *types.Map, *types.Signature:
+		return fmt.Sprintf("(%s)(0x%x)", t, words[0].Value), 1
 
-	case *ast.ForStmt:
-		if n.Init != nil {
-			Walk(v, n.Init)
+	case *types.Slice:
+		n := wordsFor(t)
+		if len(words) < n {
+			n = len(words)
 		}
-		if n.Cond != nil {
-			Walk(v, n.Cond)
-		}
-		if n.Post != nil {
-			Walk(v, n.Post)
+		switch n {
+		case 3:
+			return fmt.Sprintf("%s(0x%x len=%d cap=%d)", t, words[0].Value, words[1].Value, words[2].Value), 3
+		default:
+			return fmt.Sprintf("(%s)(0x%x)", t, words[0].Value), n
 		}
-		Walk(v, n.Body)
 
-	case *ast.RangeStmt:
-		if n.Key != nil {
-			Walk(v, n.Key)
+	case *types.Array, *types.Struct:
+		n := wordsFor(t)
+		if len(words) < n {
+			n = len(words)
 		}
-		if n.Value != nil {
-			Walk(v, n.Value)
+		parts := make([]string, n)
+		for i := 0; i < n; i++ {
+			parts[i] = fmt.Sprintf("0x%x", words[i].Value)
 		}
-		Walk(v, n.X)
-		Walk(v, n.Body)
+		return fmt.Sprintf("%s{%s}", t, strings.Join(parts, ", ")), n
 
-	// Declarations
-	case *ast.ValueSpec:
-		if n.Doc != nil {
-			Walk(v, n.Doc)
-		}
-		walkIdentList(v, n.Names)
-		if n.Type != nil {
-			Walk(v, n.Type)
-		}
-		walkExprList(v, n.Values)
-		if n.Comment != nil {
-			Walk(v, n.Comment)
-		}
+	default:
+		return fmt.Sprintf("(%s)(0x%x)", t, words[0].Value), 1
+	}
+}
 
-	case *ast.TypeSpec:
-		if n.Doc != nil {
-			Walk(v, n.Doc)
-		}
-		Walk(v, n.Name)
-		Walk(v, n.Type)
-		if n.Comment != nil {
-			Walk(v, n.Comment)
-		}
+// stringLit matches a double-quoted Go string literal.
+var stringLit = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
 
-	case *ast.GenDecl:
-		if n.Doc != nil {
-			Walk(v, n.Doc)
-		}
-		for _, s := range n.Specs {
-			Walk(v, s)
+// dereferenceString attempts to recover a string's content without a live
+// process or core dump to read memory from: it looks for a string literal
+// on the call's source line whose decoded length matches length, which
+// covers the common case of a literal passed straight into the call (e.g.
+// panic("boom")). It reports failure when no such literal is found, and the
+// caller falls back to printing the raw pointer and length.
+func (c *Cache) dereferenceString(path string, line int, length uint64) (string, bool) {
+	for _, s := range c.literalsOnLine(path, line) {
+		if uint64(len(s)) == length {
+			return s, true
 		}
+	}
+	return "", false
+}
 
-	case *ast.FuncDecl:
-		if n.Doc != nil {
-			Walk(v, n.Doc)
-		}
-		if n.Recv != nil {
-			Walk(v, n.Recv)
-		}
-		Walk(v, n.Name)
-		Walk(v, n.Type)
-		if n.Body != nil {
-			Walk(v, n.Body)
-		}
+// soleLiteralOnLine returns the call's source line's one and only string
+// literal, covering the common case of an error built directly from a
+// message literal (e.g. errors.New("boom")) where there's no ambiguity
+// about which literal is the message. It reports failure when the line
+// carries zero or more than one literal, since then there's no way to tell
+// which one, if any, ended up in the error.
+func (c *Cache) soleLiteralOnLine(path string, line int) (string, bool) {
+	lits := c.literalsOnLine(path, line)
+	if len(lits) != 1 {
+		return "", false
+	}
+	return lits[0], true
+}
 
-	// Files and packages
-	case *ast.File:
-		if n.Doc != nil {
-			Walk(v, n.Doc)
+// literalsOnLine returns every double-quoted string literal's decoded
+// content found on the call's source line.
+func (c *Cache) literalsOnLine(path string, line int) []string {
+	src := c.files[path]
+	if len(src) == 0 || line < 1 {
+		return nil
+	}
+	lines := bytes.Split(src, []byte{'\n'})
+	if line > len(lines) {
+		return nil
+	}
+	var out []string
+	for _, m := range stringLit.FindAllString(string(lines[line-1]), -1) {
+		if s, err := strconv.Unquote(m); err == nil {
+			out = append(out, s)
 		}
-		Walk(v, n.Name)
-		walkDeclList(v, n.Decls)
-		// don't walk n.Comments - they have been
-		// visited already through the individual
-		// nodes
+	}
+	return out
+}
 
+// wordsFor returns the number of machine words t occupies when passed as an
+// argument, mirroring Go's calling convention.
+func wordsFor(t types.Type) int {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Info()&types.IsString != 0 {
+			return 2 // data pointer + length
+		}
+		return 1
+	case *types.Interface:
+		return 2 // itab/type pointer + data pointer
+	case *types.Slice:
+		return 3 // data pointer + length + capacity
+	case *types.Array:
+		return int(u.Len()) * wordsFor(u.Elem())
+	case *types.Struct:
+		n := 0
+		for i := 0; i < u.NumFields(); i++ {
+			n += wordsFor(u.Field(i).Type())
+		}
+		return n
 	default:
+		// Pointer, Chan, Map, Signature and everything else fit in a word.
+		return 1
+	}
+}
+
+// advance drops the first n words of words, clamped to its length.
+func advance(words []Arg, n int) []Arg {
+	if n > len(words) {
+		n = len(words)
 	}
+	return words[n:]
 }
-*/
@@ -6,51 +6,83 @@ package stack
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/maruel/ut"
 )
 
 func TestAugment(t *testing.T) {
+	// Unlike the previous identifier-guessing pass, type-directed resolution
+	// needs to actually load the package, so the source is written to disk
+	// in its own module rather than faked through Cache.files.
+	dir, err := ioutil.TempDir("", "panicparse")
+	ut.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+	mainPath := filepath.Join(dir, "main.go")
+	ut.AssertEqual(t, nil, ioutil.WriteFile(mainPath, []byte(mainSource), 0600))
+	ut.AssertEqual(t, nil, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module main\n\ngo 1.16\n"), 0600))
+
 	extra := &bytes.Buffer{}
-	goroutines, err := ParseDump(bytes.NewBufferString(mainCrash), extra)
+	crash := strings.Replace(mainCrash, "/root/main.go", mainPath, -1)
+	goroutines, err := ParseDump(bytes.NewBufferString(crash), extra)
 	ut.AssertEqual(t, nil, err)
 	ut.AssertEqual(t, "\npanic: ooh\n\n", extra.String())
 	ut.AssertEqual(t, 1, len(goroutines))
 
-	cache := &Cache{
-		files: map[string][]byte{"/root/main.go": []byte(mainSource)},
-	}
+	cache := &Cache{}
 	cache.Augment(goroutines)
-	expected := []Call{
-		{
-			SourcePath: "/root/main.go",
-			Line:       4,
-			Func:       Function{"main.bar"},
-			Args: Args{
-				Values: []Arg{
-					{Value: 0x43080, Name: "string(0x43080, 3)"},
-					{Value: 0x1, Name: ""},
-				},
-			},
-		},
-		{
-			SourcePath: "/root/main.go",
-			Line:       8,
-			Func:       Function{"main.foo"},
-			Args: Args{
-				Values: []Arg{
-					{Value: 0x43080, Name: "string(0x43080, 3)"},
-				},
-			},
-		},
-		{
-			SourcePath: "/root/main.go",
-			Line:       12,
-			Func:       Function{"main.main"},
-		},
-	}
-	ut.AssertEqual(t, expected, goroutines[0].Signature.Stack)
+	stack := goroutines[0].Signature.Stack
+	ut.AssertEqual(t, 3, len(stack))
+	ut.AssertEqual(t, []string{"string(0x43080, 3)", "1"}, stack[0].Args.Processed)
+	ut.AssertEqual(t, []string{"string(0x43080, 3)"}, stack[1].Args.Processed)
+	ut.AssertEqual(t, []string(nil), stack[2].Args.Processed)
+}
+
+func TestDereferenceString(t *testing.T) {
+	c := &Cache{files: map[string][]byte{"/root/main.go": []byte(mainSource)}}
+	// foo("ooh") on line 12 carries a literal whose decoded length matches.
+	s, ok := c.dereferenceString("/root/main.go", 12, 3)
+	ut.AssertEqual(t, true, ok)
+	ut.AssertEqual(t, "ooh", s)
+
+	// panic(s) on line 4 passes a variable, not a literal: nothing to find.
+	_, ok = c.dereferenceString("/root/main.go", 4, 3)
+	ut.AssertEqual(t, false, ok)
+}
+
+func TestAugmentErrorLiteral(t *testing.T) {
+	dir, err := ioutil.TempDir("", "panicparse")
+	ut.AssertEqual(t, nil, err)
+	defer os.RemoveAll(dir)
+	mainPath := filepath.Join(dir, "main.go")
+	ut.AssertEqual(t, nil, ioutil.WriteFile(mainPath, []byte(errSource), 0600))
+	ut.AssertEqual(t, nil, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module main\n\ngo 1.16\n"), 0600))
+
+	extra := &bytes.Buffer{}
+	crash := strings.Replace(errCrash, "/root/main.go", mainPath, -1)
+	goroutines, err := ParseDump(bytes.NewBufferString(crash), extra)
+	ut.AssertEqual(t, nil, err)
+	ut.AssertEqual(t, 1, len(goroutines))
+
+	cache := &Cache{}
+	cache.Augment(goroutines)
+	ut.AssertEqual(t, []string{`error("kaboom")`}, goroutines[0].Signature.Stack[0].Args.Processed)
+}
+
+func TestSoleLiteralOnLine(t *testing.T) {
+	c := &Cache{files: map[string][]byte{"/root/main.go": []byte(mainSource)}}
+	// foo("ooh") on line 12 carries exactly one literal.
+	s, ok := c.soleLiteralOnLine("/root/main.go", 12)
+	ut.AssertEqual(t, true, ok)
+	ut.AssertEqual(t, "ooh", s)
+
+	// panic(s) on line 4 carries none.
+	_, ok = c.soleLiteralOnLine("/root/main.go", 4)
+	ut.AssertEqual(t, false, ok)
 }
 
 func TestIncomplete(t *testing.T) {
@@ -93,3 +125,26 @@ main.foo(0x43080, 0x3)
 main.main()
         /root/main.go:12 +0x34
 `
+
+const errSource = `package main
+
+import "errors"
+
+func bar(err error) {
+	panic(errors.New("kaboom"))
+}
+
+func main() {
+	bar(errors.New("boom"))
+}
+`
+
+const errCrash = `
+panic: kaboom
+
+goroutine 1 [running]:
+main.bar(0x1, 0x2)
+        /root/main.go:6 +0x1b
+main.main()
+        /root/main.go:10 +0x2a
+`
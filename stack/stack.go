@@ -0,0 +1,174 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Function is the fully qualified name of a function, e.g. "main.main" or
+// "net/http.(*Server).Serve".
+type Function struct {
+	Raw string
+}
+
+// Arg is one raw machine word of a call's argument list, as printed by the
+// runtime: an address, a small integer, or one word of a multi-word value
+// (a string's data pointer, a slice's length, and so on).
+type Arg struct {
+	Value uint64
+	Name  string
+}
+
+// Args is the raw and, once Cache.Augment has run, the resolved
+// representation of a call's arguments.
+type Args struct {
+	Values    []Arg
+	Processed []string
+}
+
+// Call is a single stack frame.
+type Call struct {
+	SourcePath string
+	Line       int
+	Func       Function
+	Args       Args
+	Kind       Kind
+	Snippet    []SnippetLine
+}
+
+// Signature is the part of a Goroutine that's comparable across goroutines
+// so that Bucket can group goroutines sharing the same panic.
+type Signature struct {
+	Stack    []Call
+	SleepMin int // minutes, 0 if not reported
+	SleepMax int // minutes, 0 if not reported
+}
+
+// Goroutine is a single parsed "goroutine N [state]:" block.
+type Goroutine struct {
+	ID     int
+	State  string
+	Locked bool
+	Signature
+	// Signals holds the GOTRACEBACK=crash signal/register preamble, if any,
+	// that preceded the dump this goroutine was parsed from. ParseDump
+	// itself never sees that preamble (see ExtractSignalPreamble); it's the
+	// caller's responsibility to attach it, as main.go and the Aggregator
+	// helpers do.
+	Signals []SignalFrame
+}
+
+// Bucket is a group of goroutines sharing the same Signature, so a dump
+// with many identical goroutines can be reported once instead of N times.
+type Bucket struct {
+	Signature  Signature
+	Goroutines []Goroutine
+}
+
+var (
+	goroutineHeaderRe = regexp.MustCompile(`^goroutine (\d+) \[(.+)\]:$`)
+	callLineRe        = regexp.MustCompile(`^([^\s(]+)\((.*)\)$`)
+	locationRe        = regexp.MustCompile(`^\s+(\S+):(\d+)(?:\s.*)?$`)
+)
+
+// ParseDump reads a goroutine dump, as panic or SIGQUIT prints it to
+// stderr, and returns the parsed goroutines. Anything before the first
+// "goroutine N [state]:" line (typically the "panic: ..." message) is
+// copied verbatim to extra instead of being parsed.
+//
+// ParseDump has no notion of the SIGSEGV/SIGABRT/... register-dump
+// preamble GOTRACEBACK=crash prints ahead of the goroutines: strip that
+// with ExtractSignalPreamble first.
+func ParseDump(r io.Reader, extra io.Writer) ([]Goroutine, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var goroutines []Goroutine
+	var cur *Goroutine
+	inPreamble := true
+	for s.Scan() {
+		line := s.Text()
+		if m := goroutineHeaderRe.FindStringSubmatch(line); m != nil {
+			inPreamble = false
+			goroutines = append(goroutines, newGoroutine(m))
+			cur = &goroutines[len(goroutines)-1]
+			continue
+		}
+		if inPreamble {
+			if _, err := io.WriteString(extra, line+"\n"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if call := parseCallLine(line); call != nil {
+			cur.Signature.Stack = append(cur.Signature.Stack, *call)
+			continue
+		}
+		parseLocationLine(cur, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return goroutines, nil
+}
+
+func newGoroutine(m []string) Goroutine {
+	id, _ := strconv.Atoi(m[1])
+	parts := strings.Split(m[2], ", ")
+	g := Goroutine{ID: id, State: parts[0]}
+	for _, p := range parts[1:] {
+		switch {
+		case p == "locked to thread":
+			g.Locked = true
+		case strings.HasSuffix(p, " minutes"):
+			if n, err := strconv.Atoi(strings.TrimSuffix(p, " minutes")); err == nil {
+				g.Signature.SleepMin, g.Signature.SleepMax = n, n
+			}
+		}
+	}
+	return g
+}
+
+// parseCallLine parses a "pkg.Func(0x1, 0x2)" frame line, or nil if line
+// isn't one (e.g. it's the file:line that follows it instead).
+func parseCallLine(line string) *Call {
+	m := callLineRe.FindStringSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	call := &Call{Func: Function{Raw: m[1]}}
+	if m[2] != "" {
+		for _, a := range strings.Split(m[2], ", ") {
+			v, err := strconv.ParseUint(strings.TrimPrefix(a, "0x"), 16, 64)
+			if err != nil {
+				continue
+			}
+			call.Args.Values = append(call.Args.Values, Arg{Value: v})
+		}
+	}
+	return call
+}
+
+// parseLocationLine parses the "\t/path/to/file.go:123 +0x45" line that
+// follows a call line, filling in the most recently added Call's source
+// location and Kind.
+func parseLocationLine(g *Goroutine, line string) {
+	m := locationRe.FindStringSubmatch(line)
+	if m == nil || len(g.Signature.Stack) == 0 {
+		return
+	}
+	idx := len(g.Signature.Stack) - 1
+	call := &g.Signature.Stack[idx]
+	call.SourcePath = m[1]
+	call.Line, _ = strconv.Atoi(m[2])
+	call.Kind = classifyFrame(call.SourcePath, call.Func.Raw)
+}
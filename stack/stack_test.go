@@ -0,0 +1,49 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maruel/ut"
+)
+
+func TestParseDumpLockedAndSleep(t *testing.T) {
+	dump := `goroutine 5 [chan receive, 2 minutes, locked to thread]:
+main.wait(0x1)
+	/root/main.go:20 +0x10
+`
+	extra := &bytes.Buffer{}
+	goroutines, err := ParseDump(bytes.NewBufferString(dump), extra)
+	ut.AssertEqual(t, nil, err)
+	ut.AssertEqual(t, "", extra.String())
+	ut.AssertEqual(t, 1, len(goroutines))
+	g := goroutines[0]
+	ut.AssertEqual(t, 5, g.ID)
+	ut.AssertEqual(t, "chan receive", g.State)
+	ut.AssertEqual(t, true, g.Locked)
+	ut.AssertEqual(t, 2, g.Signature.SleepMax)
+	ut.AssertEqual(t, 1, len(g.Signature.Stack))
+	ut.AssertEqual(t, "main.wait", g.Signature.Stack[0].Func.Raw)
+	ut.AssertEqual(t, "/root/main.go", g.Signature.Stack[0].SourcePath)
+	ut.AssertEqual(t, 20, g.Signature.Stack[0].Line)
+}
+
+func TestParseDumpMultipleGoroutines(t *testing.T) {
+	dump := `goroutine 1 [running]:
+main.main()
+	/root/main.go:12 +0x34
+goroutine 2 [select]:
+main.loop()
+	/root/main.go:30 +0x1
+`
+	goroutines, err := ParseDump(bytes.NewBufferString(dump), &bytes.Buffer{})
+	ut.AssertEqual(t, nil, err)
+	ut.AssertEqual(t, 2, len(goroutines))
+	ut.AssertEqual(t, 1, goroutines[0].ID)
+	ut.AssertEqual(t, 2, goroutines[1].ID)
+	ut.AssertEqual(t, "select", goroutines[1].State)
+}
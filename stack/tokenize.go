@@ -0,0 +1,61 @@
+// Copyright 2015 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package stack
+
+import (
+	"go/scanner"
+	"go/token"
+)
+
+// Token is one piece of a line of Go source as split by Tokenize: either a
+// single lexical token (Kind set accordingly) or a run of text go/scanner
+// didn't tokenize at all, such as whitespace between tokens, reported with
+// the zero Kind (token.ILLEGAL).
+type Token struct {
+	Text string
+	Kind token.Token
+}
+
+// Tokenize splits a single line of Go source into Tokens. It's the shared
+// scanning logic behind both ANSI terminal highlighting (highlightLine)
+// and HTML span wrapping (report.tokenizeHTML), factored out so the two
+// renderers can't drift apart on how they walk go/scanner's output.
+//
+// go/scanner's automatic semicolon insertion reports a synthetic
+// token.SEMICOLON at end-of-line with no corresponding source text (its
+// offset is len(line)); Tokenize drops it rather than emitting a Token a
+// caller might mistakenly try to consume past the end of the line.
+func Tokenize(line string) []Token {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(line))
+	var s scanner.Scanner
+	s.Init(file, []byte(line), nil, scanner.ScanComments)
+
+	var out []Token
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		offset := fset.Position(pos).Offset
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		if last < offset {
+			out = append(out, Token{Text: line[last:offset]})
+		}
+		out = append(out, Token{Text: text, Kind: tok})
+		last = offset + len(text)
+	}
+	if last < len(line) {
+		out = append(out, Token{Text: line[last:]})
+	}
+	return out
+}